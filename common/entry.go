@@ -8,7 +8,6 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
-	"fmt"
 )
 
 // An Entry is the element which carries user data
@@ -92,49 +91,16 @@ func (e *Entry) MarshalExtIDsBinary() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func (e *Entry) UnmarshalBinary(d []byte) (err error) {
-	buf := bytes.NewBuffer(d)
-
-	// 1 byte Version
-	e.Version, err = buf.ReadByte()
+// UnmarshalBinary is a thin wrapper around the default EntryDecoder, kept
+// for backward compatibility. Callers decoding entries off the network
+// should use EntryDecoder directly so they can set MaxExtIDsSize,
+// MaxExtIDSize and MaxContentSize for the untrusted data they're reading.
+func (e *Entry) UnmarshalBinary(d []byte) error {
+	decoded, err := NewEntryDecoder().Decode(bytes.NewReader(d))
 	if err != nil {
 		return err
 	}
 
-	// 32 byte ChainID
-	e.ChainID = new(Hash)
-	e.ChainID.Bytes = make([]byte, 32)
-	if _, err := buf.Read(e.ChainID.Bytes); err != nil {
-		return err
-	}
-
-	// 2 byte size of ExtIDs
-	var extSize uint16
-	if err := binary.Read(buf, binary.BigEndian, &extSize); err != nil {
-		return err
-	}
-
-	// ExtIDs
-	for i := extSize; i > 0; {
-		var xsize int16
-		binary.Read(buf, binary.BigEndian, &xsize)
-		i -= 2
-
-		x := make([]byte, xsize)
-		if n, err := buf.Read(x); err != nil {
-			return err
-		} else {
-			if c := cap(x); n != c {
-				return fmt.Errorf("Could not read ExtID: Read %d bytes of %d\n",
-					n, c)
-			}
-			e.ExtIDs = append(e.ExtIDs, x)
-			i -= uint16(n)
-		}
-	}
-
-	// Content
-	e.Content = buf.Bytes()
-
+	*e = *decoded
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package common
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/ugorji/go/codec"
+)
+
+var cborHandle = new(codec.CborHandle)
+
+// entryCBOR mirrors entryJSON so the CBOR codec carries the same hex
+// encoded, self-describing fields rather than raw binary blobs.
+type entryCBOR struct {
+	Version int
+	ChainID string
+	ExtIDs  []string
+	Content string
+}
+
+// MarshalCBOR returns the CBOR encoding of the Entry, using the same hex
+// encoded fields as MarshalJSON.
+func (e *Entry) MarshalCBOR() ([]byte, error) {
+	extids := make([]string, len(e.ExtIDs))
+	for i, x := range e.ExtIDs {
+		extids[i] = hex.EncodeToString(x)
+	}
+
+	var chainid string
+	if e.ChainID != nil {
+		chainid = hex.EncodeToString(e.ChainID.Bytes)
+	}
+
+	tmp := &entryCBOR{
+		Version: int(e.Version),
+		ChainID: chainid,
+		ExtIDs:  extids,
+		Content: hex.EncodeToString(e.Content),
+	}
+
+	buf := new(bytes.Buffer)
+	enc := codec.NewEncoder(buf, cborHandle)
+	if err := enc.Encode(tmp); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalCBOR populates the Entry from its CBOR encoding.
+func (e *Entry) UnmarshalCBOR(data []byte) error {
+	tmp := new(entryCBOR)
+	dec := codec.NewDecoder(bytes.NewReader(data), cborHandle)
+	if err := dec.Decode(tmp); err != nil {
+		return err
+	}
+
+	chainid, err := hex.DecodeString(tmp.ChainID)
+	if err != nil {
+		return err
+	}
+
+	extids := make([][]byte, len(tmp.ExtIDs))
+	for i, x := range tmp.ExtIDs {
+		b, err := hex.DecodeString(x)
+		if err != nil {
+			return err
+		}
+		extids[i] = b
+	}
+
+	content, err := hex.DecodeString(tmp.Content)
+	if err != nil {
+		return err
+	}
+
+	e.Version = uint8(tmp.Version)
+	e.ChainID = &Hash{Bytes: chainid}
+	e.ExtIDs = extids
+	e.Content = content
+
+	return nil
+}
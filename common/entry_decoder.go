@@ -0,0 +1,164 @@
+// Copyright 2015 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package common
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Default size limits used by NewEntryDecoder. These are generous enough
+// for any entry factomd will relay, while still bounding how much memory
+// a single malicious entry can force us to allocate.
+const (
+	DefaultMaxExtIDsSize  = 10 * 1024
+	DefaultMaxExtIDSize   = 10 * 1024
+	DefaultMaxContentSize = 10 * 1024
+)
+
+var (
+	// ErrTruncated is returned when the reader runs out of data before a
+	// complete entry has been decoded.
+	ErrTruncated = errors.New("common: entry truncated")
+
+	// ErrExtIDOverflow is returned when the declared size of the ExtIDs
+	// block, or of a single ExtID within it, exceeds the decoder's
+	// configured limit.
+	ErrExtIDOverflow = errors.New("common: ExtIDs exceed configured size limit")
+
+	// ErrContentOverflow is returned when Content exceeds the decoder's
+	// configured MaxContentSize.
+	ErrContentOverflow = errors.New("common: Content exceeds configured size limit")
+)
+
+// BufferPool hands the decoder a []byte of the requested length, letting
+// callers reuse buffers across many Decode calls instead of allocating one
+// per ExtID.
+type BufferPool func(size int) []byte
+
+// EntryDecoder decodes Entries from the binary wire format with
+// configurable hard limits, so decoding data received from the network
+// can't be made to allocate unbounded memory.
+type EntryDecoder struct {
+	// MaxExtIDsSize bounds the total declared size of the ExtIDs block.
+	MaxExtIDsSize int
+
+	// MaxExtIDSize bounds the declared size of any single ExtID.
+	MaxExtIDSize int
+
+	// MaxContentSize bounds the size of Content.
+	MaxContentSize int
+
+	// Pool, if set, is used to obtain the backing buffer for each ExtID
+	// instead of allocating a new one.
+	Pool BufferPool
+}
+
+// NewEntryDecoder returns an EntryDecoder using the package's default size
+// limits and no buffer pool.
+func NewEntryDecoder() *EntryDecoder {
+	return &EntryDecoder{
+		MaxExtIDsSize:  DefaultMaxExtIDsSize,
+		MaxExtIDSize:   DefaultMaxExtIDSize,
+		MaxContentSize: DefaultMaxContentSize,
+	}
+}
+
+// Decode reads a single Entry from r in the binary wire format, using
+// uint16 throughout for ExtID sizes and enforcing d's configured limits.
+func (d *EntryDecoder) Decode(r io.Reader) (*Entry, error) {
+	e := new(Entry)
+
+	var header [33]byte // 1 byte Version + 32 byte ChainID
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, wrapTruncated(err)
+	}
+	e.Version = header[0]
+	e.ChainID = &Hash{Bytes: append([]byte(nil), header[1:]...)}
+
+	var extSizeBuf [2]byte
+	if _, err := io.ReadFull(r, extSizeBuf[:]); err != nil {
+		return nil, wrapTruncated(err)
+	}
+	extSize := int(binary.BigEndian.Uint16(extSizeBuf[:]))
+	if extSize > d.maxExtIDsSize() {
+		return nil, ErrExtIDOverflow
+	}
+
+	for remaining := extSize; remaining > 0; {
+		if remaining < 2 {
+			return nil, ErrTruncated
+		}
+
+		var sizeBuf [2]byte
+		if _, err := io.ReadFull(r, sizeBuf[:]); err != nil {
+			return nil, wrapTruncated(err)
+		}
+		remaining -= 2
+
+		xsize := int(binary.BigEndian.Uint16(sizeBuf[:]))
+		if xsize > d.maxExtIDSize() || xsize > remaining {
+			return nil, ErrExtIDOverflow
+		}
+
+		x := d.buffer(xsize)
+		if _, err := io.ReadFull(r, x); err != nil {
+			return nil, wrapTruncated(err)
+		}
+		e.ExtIDs = append(e.ExtIDs, x)
+		remaining -= xsize
+	}
+
+	content := make([]byte, d.maxContentSize()+1)
+	n, err := io.ReadFull(r, content)
+	switch err {
+	case io.EOF, io.ErrUnexpectedEOF:
+		e.Content = content[:n]
+	case nil:
+		return nil, ErrContentOverflow
+	default:
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (d *EntryDecoder) buffer(size int) []byte {
+	if d.Pool == nil {
+		return make([]byte, size)
+	}
+	return d.Pool(size)[:size]
+}
+
+func (d *EntryDecoder) maxExtIDsSize() int {
+	if d.MaxExtIDsSize > 0 {
+		return d.MaxExtIDsSize
+	}
+	return DefaultMaxExtIDsSize
+}
+
+func (d *EntryDecoder) maxExtIDSize() int {
+	if d.MaxExtIDSize > 0 {
+		return d.MaxExtIDSize
+	}
+	return DefaultMaxExtIDSize
+}
+
+func (d *EntryDecoder) maxContentSize() int {
+	if d.MaxContentSize > 0 {
+		return d.MaxContentSize
+	}
+	return DefaultMaxContentSize
+}
+
+// wrapTruncated normalizes any read error short of a full header/ExtID/
+// size field into ErrTruncated.
+func wrapTruncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+// Copyright 2015 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package common
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// entryJSON is the wire representation used by MarshalJSON/UnmarshalJSON.
+// ChainID, ExtIDs and Content are hex encoded, matching the convention
+// already relied on by external tools that round-trip entries through
+// Entry.JSONByte().
+type entryJSON struct {
+	Version int      `json:"version"`
+	ChainID string   `json:"chainid"`
+	ExtIDs  []string `json:"extids"`
+	Content string   `json:"content"`
+}
+
+// MarshalJSON implements the json.Marshaler interface. ChainID, ExtIDs and
+// Content are hex encoded.
+func (e *Entry) MarshalJSON() ([]byte, error) {
+	extids := make([]string, len(e.ExtIDs))
+	for i, x := range e.ExtIDs {
+		extids[i] = hex.EncodeToString(x)
+	}
+
+	var chainid string
+	if e.ChainID != nil {
+		chainid = hex.EncodeToString(e.ChainID.Bytes)
+	}
+
+	return json.Marshal(&entryJSON{
+		Version: int(e.Version),
+		ChainID: chainid,
+		ExtIDs:  extids,
+		Content: hex.EncodeToString(e.Content),
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	tmp := new(entryJSON)
+	if err := json.Unmarshal(data, tmp); err != nil {
+		return err
+	}
+
+	chainid, err := hex.DecodeString(tmp.ChainID)
+	if err != nil {
+		return err
+	}
+
+	extids := make([][]byte, len(tmp.ExtIDs))
+	for i, x := range tmp.ExtIDs {
+		b, err := hex.DecodeString(x)
+		if err != nil {
+			return err
+		}
+		extids[i] = b
+	}
+
+	content, err := hex.DecodeString(tmp.Content)
+	if err != nil {
+		return err
+	}
+
+	e.Version = uint8(tmp.Version)
+	e.ChainID = &Hash{Bytes: chainid}
+	e.ExtIDs = extids
+	e.Content = content
+
+	return nil
+}
+
+// JSONByte returns the JSON encoding of the Entry.
+func (e *Entry) JSONByte() ([]byte, error) {
+	return e.MarshalJSON()
+}
+
+// JSONString returns the JSON encoding of the Entry as a string.
+func (e *Entry) JSONString() (string, error) {
+	b, err := e.JSONByte()
+	return string(b), err
+}
+
+// CanonicalBytes returns a deterministic binary encoding of the Entry that
+// is independent of the field ordering used by any particular serialization
+// (JSON, CBOR, ...). It is the form that should always be hashed or signed,
+// since MarshalBinary's trailing Content bytes are otherwise ambiguous
+// without a length prefix.
+func (e *Entry) CanonicalBytes() []byte {
+	buf := new(bytes.Buffer)
+
+	binary.Write(buf, binary.BigEndian, e.Version)
+
+	if e.ChainID != nil {
+		buf.Write(e.ChainID.Bytes)
+	}
+
+	binary.Write(buf, binary.BigEndian, uint16(len(e.ExtIDs)))
+	for _, x := range e.ExtIDs {
+		binary.Write(buf, binary.BigEndian, uint16(len(x)))
+		buf.Write(x)
+	}
+
+	binary.Write(buf, binary.BigEndian, uint32(len(e.Content)))
+	buf.Write(e.Content)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,164 @@
+// Copyright 2015 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package common
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/FactomProject/btcd/btcec"
+)
+
+// sigExtIDTag marks an entry whose first three ExtIDs carry a detached
+// signature over CanonicalBytes(), as ExtIDs[0]=sigExtIDTag,
+// ExtIDs[1]=pubkey, ExtIDs[2]=signature.
+const sigExtIDTag = "sig:v1"
+
+// EntrySigner produces a detached signature over an Entry's canonical bytes
+// and exposes the public key that verifies it.
+type EntrySigner interface {
+	Sign(e *Entry) ([]byte, error)
+	PublicKey() []byte
+}
+
+// EntryVerifier checks a detached signature over an Entry's canonical bytes.
+type EntryVerifier interface {
+	Verify(e *Entry, pub, sig []byte) bool
+}
+
+// Secp256k1Signer is the concrete EntrySigner/EntryVerifier backed by
+// btcec's secp256k1 implementation.
+type Secp256k1Signer struct {
+	priv *btcec.PrivateKey
+}
+
+// NewSecp256k1Signer wraps a raw 32 byte secp256k1 private key as an
+// EntrySigner/EntryVerifier.
+func NewSecp256k1Signer(priv []byte) *Secp256k1Signer {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), priv)
+	return &Secp256k1Signer{priv: key}
+}
+
+// Sign implements EntrySigner.
+func (s *Secp256k1Signer) Sign(e *Entry) ([]byte, error) {
+	hash := sha256.Sum256(e.CanonicalBytes())
+	sig, err := s.priv.Sign(hash[:])
+	if err != nil {
+		return nil, err
+	}
+	return sig.Serialize(), nil
+}
+
+// PublicKey implements EntrySigner.
+func (s *Secp256k1Signer) PublicKey() []byte {
+	return s.priv.PubKey().SerializeCompressed()
+}
+
+// Verify implements EntryVerifier.
+func (s *Secp256k1Signer) Verify(e *Entry, pub, sig []byte) bool {
+	return verifySecp256k1(e, pub, sig)
+}
+
+func verifySecp256k1(e *Entry, pub, sig []byte) bool {
+	pubKey, err := btcec.ParsePubKey(pub, btcec.S256())
+	if err != nil {
+		return false
+	}
+
+	signature, err := btcec.ParseSignature(sig, btcec.S256())
+	if err != nil {
+		return false
+	}
+
+	hash := sha256.Sum256(e.CanonicalBytes())
+	return signature.Verify(hash[:], pubKey)
+}
+
+// Sign signs e's CanonicalBytes() with signer and modifies e in place,
+// inserting the reserved signature block (sigExtIDTag, pubkey, signature)
+// as the first three ExtIDs, ahead of e's existing ExtIDs.
+func (e *Entry) Sign(signer EntrySigner) error {
+	sig, err := signer.Sign(e)
+	if err != nil {
+		return err
+	}
+
+	header := [][]byte{[]byte(sigExtIDTag), signer.PublicKey(), sig}
+	e.ExtIDs = append(header, e.ExtIDs...)
+
+	return nil
+}
+
+// VerifySignature reports whether the Entry carries a valid sigExtIDTag
+// signature block over its own CanonicalBytes(). The signature block
+// itself is excluded from the bytes that were signed.
+func (e *Entry) VerifySignature() bool {
+	pub, sig, ok := e.SignatureBlock()
+	if !ok {
+		return false
+	}
+
+	unsigned := &Entry{
+		Version: e.Version,
+		ChainID: e.ChainID,
+		ExtIDs:  e.ExtIDs[3:],
+		Content: e.Content,
+	}
+
+	return verifySecp256k1(unsigned, pub, sig)
+}
+
+// SignatureBlock extracts the pubkey and signature from a Sign()'d Entry's
+// reserved leading ExtIDs, reporting false if none is present.
+func (e *Entry) SignatureBlock() (pub, sig []byte, ok bool) {
+	if len(e.ExtIDs) < 3 || !bytes.Equal(e.ExtIDs[0], []byte(sigExtIDTag)) {
+		return nil, nil, false
+	}
+	return e.ExtIDs[1], e.ExtIDs[2], true
+}
+
+// ChainPolicy pins the set of pubkeys allowed to sign entries appended to
+// a chain, as determined by inspecting the chain's first entry.
+type ChainPolicy struct {
+	ChainID    *Hash
+	SignerKeys [][]byte
+}
+
+// NewChainPolicyFromFirstEntry builds a ChainPolicy by reading the
+// signature block off of a chain's first entry. The first entry's signer
+// becomes the sole allowed signer for the chain.
+func NewChainPolicyFromFirstEntry(first *Entry) (*ChainPolicy, error) {
+	pub, _, ok := first.SignatureBlock()
+	if !ok {
+		return nil, fmt.Errorf("first entry of chain %x is not signed", first.ChainID.Bytes)
+	}
+
+	return &ChainPolicy{
+		ChainID:    first.ChainID,
+		SignerKeys: [][]byte{pub},
+	}, nil
+}
+
+// Allows reports whether e is both validly signed and signed by one of the
+// policy's pinned keys.
+func (p *ChainPolicy) Allows(e *Entry) bool {
+	if e.ChainID == nil || p.ChainID == nil || !bytes.Equal(e.ChainID.Bytes, p.ChainID.Bytes) {
+		return false
+	}
+
+	pub, _, ok := e.SignatureBlock()
+	if !ok || !e.VerifySignature() {
+		return false
+	}
+
+	for _, k := range p.SignerKeys {
+		if bytes.Equal(k, pub) {
+			return true
+		}
+	}
+
+	return false
+}
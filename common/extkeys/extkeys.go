@@ -0,0 +1,407 @@
+// Copyright 2015 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+// Package extkeys implements BIP32 hierarchical deterministic key
+// derivation over secp256k1, so a wallet can derive per-chain and
+// per-entry signing keys from a single seed rather than storing one
+// private key per chain.
+package extkeys
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/FactomProject/btcd/btcec"
+	"github.com/FactomProject/btcutil/base58"
+	"golang.org/x/crypto/ripemd160"
+
+	"github.com/shellsec/FactomCode/common"
+)
+
+// HardenedKeyStart is the index of the first hardened child key, per
+// BIP32. Indexes at or above this use hardened derivation.
+const HardenedKeyStart = uint32(0x80000000)
+
+// FactomCoinType is the coin_type field used in Factom's BIP44 paths
+// (m / 44' / FactomCoinType' / account' / chain / index).
+const FactomCoinType = 131
+
+// masterKeySeed is the HMAC-SHA512 key used to derive a master key from a
+// seed, as fixed by BIP32.
+var masterKeySeed = []byte("Bitcoin seed")
+
+var (
+	// ErrInvalidSeedLength is returned by NewMaster when the seed is
+	// outside BIP32's [128, 512] bit range.
+	ErrInvalidSeedLength = errors.New("extkeys: seed length must be between 128 and 512 bits")
+
+	// ErrInvalidChild is returned by Child when the derived key is
+	// invalid (I_L >= n or the resulting private key is zero). Per
+	// BIP32 the caller should retry with the next index.
+	ErrInvalidChild = errors.New("extkeys: invalid child, skip to next index")
+
+	// ErrInvalidKeyLen is returned when parsing a serialized extended
+	// key that isn't exactly 78 bytes.
+	ErrInvalidKeyLen = errors.New("extkeys: serialized extended key must be 78 bytes")
+
+	// ErrBadChecksum is returned when a Base58Check-decoded extended
+	// key fails its checksum.
+	ErrBadChecksum = errors.New("extkeys: bad extended key checksum")
+)
+
+// hdVersionPrivate and hdVersionPublic are the 4 byte version prefixes of
+// a serialized extended private and public key.
+// Factom uses a single version pair for both mainnet and testnet
+// extended keys; callers that need network separation can derive
+// distinct seeds instead.
+var (
+	hdVersionPrivate = [4]byte{0x03, 0x3e, 0x80, 0x94}
+	hdVersionPublic  = [4]byte{0x03, 0x3e, 0x80, 0x98}
+)
+
+// ExtendedKey is a BIP32 extended private or public key.
+type ExtendedKey struct {
+	key       []byte // 32 byte private key, or 33 byte compressed public key
+	chainCode []byte // 32 bytes
+	depth     uint8
+	parentFP  []byte // first 4 bytes of the parent's identifier
+	childNum  uint32
+	isPrivate bool
+}
+
+// NewMaster derives a master extended private key from a seed, following
+// BIP32: I = HMAC-SHA512(key="Bitcoin seed", data=seed); I_L becomes the
+// master private key and I_R the master chain code.
+func NewMaster(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, ErrInvalidSeedLength
+	}
+
+	h := hmac.New(sha512.New, masterKeySeed)
+	h.Write(seed)
+	sum := h.Sum(nil)
+
+	il, ir := sum[:32], sum[32:]
+
+	if !validPrivateKey(il) {
+		return nil, ErrInvalidChild
+	}
+
+	return &ExtendedKey{
+		key:       il,
+		chainCode: ir,
+		depth:     0,
+		parentFP:  []byte{0x00, 0x00, 0x00, 0x00},
+		childNum:  0,
+		isPrivate: true,
+	}, nil
+}
+
+// validPrivateKey reports whether key is a valid, non-zero secp256k1
+// private key scalar less than the curve order n.
+func validPrivateKey(key []byte) bool {
+	n := new(big.Int).SetBytes(key)
+	return n.Sign() != 0 && n.Cmp(btcec.S256().N) < 0
+}
+
+// IsPrivate reports whether k holds a private key.
+func (k *ExtendedKey) IsPrivate() bool {
+	return k.isPrivate
+}
+
+// Depth returns the number of derivation steps from the master key to k.
+func (k *ExtendedKey) Depth() uint8 {
+	return k.depth
+}
+
+// ECPrivKey returns k's private key as a btcec.PrivateKey. It fails if k
+// is a public-only extended key.
+func (k *ExtendedKey) ECPrivKey() (*btcec.PrivateKey, error) {
+	if !k.isPrivate {
+		return nil, fmt.Errorf("extkeys: extended key is public-only")
+	}
+	priv, _ := btcec.PrivKeyFromBytes(btcec.S256(), k.key)
+	return priv, nil
+}
+
+// ECPubKey returns k's public key, deriving it from the private key if
+// necessary.
+func (k *ExtendedKey) ECPubKey() (*btcec.PublicKey, error) {
+	if !k.isPrivate {
+		return btcec.ParsePubKey(k.key, btcec.S256())
+	}
+	priv, err := k.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	return priv.PubKey(), nil
+}
+
+// pubKeyBytes returns the 33 byte compressed public key for k, whether k
+// is a private or public extended key.
+func (k *ExtendedKey) pubKeyBytes() ([]byte, error) {
+	if !k.isPrivate {
+		return k.key, nil
+	}
+	pub, err := k.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	return pub.SerializeCompressed(), nil
+}
+
+// fingerprint returns the first 4 bytes of RIPEMD160(SHA256(pubkey)),
+// which identifies k as a parent for its children.
+func (k *ExtendedKey) fingerprint() ([]byte, error) {
+	pub, err := k.pubKeyBytes()
+	if err != nil {
+		return nil, err
+	}
+	sha := sha256.Sum256(pub)
+	r := ripemd160.New()
+	r.Write(sha[:])
+	return r.Sum(nil)[:4], nil
+}
+
+// Child derives the i'th child of k. Indexes >= HardenedKeyStart use
+// hardened derivation and require k to be a private key. Per BIP32, a
+// derivation that yields an invalid key returns ErrInvalidChild; the
+// caller should retry with i+1.
+func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
+	isHardened := i >= HardenedKeyStart
+	if isHardened && !k.isPrivate {
+		return nil, fmt.Errorf("extkeys: cannot derive a hardened child from a public key")
+	}
+
+	var data []byte
+	if isHardened {
+		data = append([]byte{0x00}, k.key...)
+	} else {
+		pub, err := k.pubKeyBytes()
+		if err != nil {
+			return nil, err
+		}
+		data = pub
+	}
+
+	var childNumBytes [4]byte
+	binary.BigEndian.PutUint32(childNumBytes[:], i)
+	data = append(data, childNumBytes[:]...)
+
+	h := hmac.New(sha512.New, k.chainCode)
+	h.Write(data)
+	sum := h.Sum(nil)
+	il, ir := sum[:32], sum[32:]
+
+	ilNum := new(big.Int).SetBytes(il)
+	if ilNum.Cmp(btcec.S256().N) >= 0 {
+		return nil, ErrInvalidChild
+	}
+
+	parentFP, err := k.fingerprint()
+	if err != nil {
+		return nil, err
+	}
+
+	if k.isPrivate {
+		keyNum := new(big.Int).SetBytes(k.key)
+		childNum := new(big.Int).Add(ilNum, keyNum)
+		childNum.Mod(childNum, btcec.S256().N)
+		if childNum.Sign() == 0 {
+			return nil, ErrInvalidChild
+		}
+
+		childKey := make([]byte, 32)
+		copyPadded(childKey, childNum.Bytes())
+
+		return &ExtendedKey{
+			key:       childKey,
+			chainCode: ir,
+			depth:     k.depth + 1,
+			parentFP:  parentFP,
+			childNum:  i,
+			isPrivate: true,
+		}, nil
+	}
+
+	ilPoint := new(btcec.PrivateKey)
+	ilPoint.Curve = btcec.S256()
+	ilPoint.X, ilPoint.Y = btcec.S256().ScalarBaseMult(il)
+
+	parentPub, err := btcec.ParsePubKey(k.key, btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+
+	childX, childY := btcec.S256().Add(ilPoint.X, ilPoint.Y, parentPub.X, parentPub.Y)
+	if childX.Sign() == 0 && childY.Sign() == 0 {
+		return nil, ErrInvalidChild
+	}
+
+	childPub := (&btcec.PublicKey{Curve: btcec.S256(), X: childX, Y: childY}).SerializeCompressed()
+
+	return &ExtendedKey{
+		key:       childPub,
+		chainCode: ir,
+		depth:     k.depth + 1,
+		parentFP:  parentFP,
+		childNum:  i,
+		isPrivate: false,
+	}, nil
+}
+
+// copyPadded copies src into the tail of dst, left-padding with zero
+// bytes so a shorter big.Int byte slice still fills a fixed-width field.
+func copyPadded(dst, src []byte) {
+	copy(dst[len(dst)-len(src):], src)
+}
+
+// Neuter returns the public-key-only version of k, suitable for handing
+// to a watch-only signer that should never see the private key.
+func (k *ExtendedKey) Neuter() (*ExtendedKey, error) {
+	if !k.isPrivate {
+		return k, nil
+	}
+
+	pub, err := k.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExtendedKey{
+		key:       pub.SerializeCompressed(),
+		chainCode: k.chainCode,
+		depth:     k.depth,
+		parentFP:  k.parentFP,
+		childNum:  k.childNum,
+		isPrivate: false,
+	}, nil
+}
+
+// String serializes k as a Base58Check-encoded 78 byte extended key.
+func (k *ExtendedKey) String() string {
+	var version [4]byte
+	if k.isPrivate {
+		version = hdVersionPrivate
+	} else {
+		version = hdVersionPublic
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write(version[:])
+	buf.WriteByte(k.depth)
+	buf.Write(k.parentFP)
+
+	var childNum [4]byte
+	binary.BigEndian.PutUint32(childNum[:], k.childNum)
+	buf.Write(childNum[:])
+
+	buf.Write(k.chainCode)
+
+	if k.isPrivate {
+		buf.WriteByte(0x00)
+		padded := make([]byte, 32)
+		copyPadded(padded, k.key)
+		buf.Write(padded)
+	} else {
+		buf.Write(k.key)
+	}
+
+	return base58.CheckEncode(buf.Bytes(), 0)
+}
+
+// NewKeyFromString parses a Base58Check-encoded extended key, as produced
+// by String.
+func NewKeyFromString(s string) (*ExtendedKey, error) {
+	decoded, _, err := base58.CheckDecode(s)
+	if err == base58.ErrChecksum {
+		return nil, ErrBadChecksum
+	}
+	if err != nil {
+		return nil, err
+	}
+	// String encodes with a single-byte checksum version of 0, so
+	// CheckDecode's version byte carries no information here and decoded
+	// is already the full 78 byte payload.
+	return parseExtendedKey(decoded)
+}
+
+func parseExtendedKey(data []byte) (*ExtendedKey, error) {
+	if len(data) != 78 {
+		return nil, ErrInvalidKeyLen
+	}
+
+	version := data[0:4]
+	depth := data[4]
+	parentFP := data[5:9]
+	childNum := binary.BigEndian.Uint32(data[9:13])
+	chainCode := data[13:45]
+	keyData := data[45:78]
+
+	isPrivate := bytes.Equal(version, hdVersionPrivate[:])
+
+	k := &ExtendedKey{
+		chainCode: chainCode,
+		depth:     depth,
+		parentFP:  parentFP,
+		childNum:  childNum,
+		isPrivate: isPrivate,
+	}
+
+	if isPrivate {
+		k.key = keyData[1:]
+	} else {
+		k.key = keyData
+	}
+
+	return k, nil
+}
+
+// DerivePath walks a BIP44-style path such as "m/44'/131'/0'/0/0" from k,
+// treating a trailing apostrophe on a path element as a request for
+// hardened derivation.
+func (k *ExtendedKey) DerivePath(path string) (*ExtendedKey, error) {
+	elements := strings.Split(path, "/")
+	if len(elements) == 0 || elements[0] != "m" {
+		return nil, fmt.Errorf("extkeys: path must start with \"m\": %q", path)
+	}
+
+	cur := k
+	for _, e := range elements[1:] {
+		hardened := strings.HasSuffix(e, "'")
+		e = strings.TrimSuffix(e, "'")
+
+		idx, err := strconv.ParseUint(e, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("extkeys: bad path element %q: %v", e, err)
+		}
+		if hardened {
+			idx += uint64(HardenedKeyStart)
+		}
+
+		cur, err = cur.Child(uint32(idx))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cur, nil
+}
+
+// Signer returns a common.EntrySigner backed by k's private key.
+func (k *ExtendedKey) Signer() (common.EntrySigner, error) {
+	priv, err := k.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	return common.NewSecp256k1Signer(priv.Serialize()), nil
+}
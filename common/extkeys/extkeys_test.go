@@ -0,0 +1,151 @@
+// Copyright 2015 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package extkeys
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// TestNewMaster checks NewMaster against BIP32 test vector 1's seed,
+// comparing the raw private key and chain code rather than the xprv
+// string, since this package uses its own version bytes.
+func TestNewMaster(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+
+	wantPriv := "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"
+	wantChain := "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508"
+
+	k, err := NewMaster(seed)
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+
+	priv, err := k.ECPrivKey()
+	if err != nil {
+		t.Fatalf("ECPrivKey: %v", err)
+	}
+
+	if got := hex.EncodeToString(priv.Serialize()); got != wantPriv {
+		t.Errorf("master priv = %s, want %s", got, wantPriv)
+	}
+	if got := hex.EncodeToString(k.chainCode); got != wantChain {
+		t.Errorf("master chain code = %s, want %s", got, wantChain)
+	}
+}
+
+// TestChildHardened checks the hardened child m/0' of BIP32 test vector 1.
+func TestChildHardened(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+
+	wantPriv := "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"
+	wantChain := "47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141"
+
+	master, err := NewMaster(seed)
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+
+	child, err := master.Child(HardenedKeyStart)
+	if err != nil {
+		t.Fatalf("Child: %v", err)
+	}
+
+	priv, err := child.ECPrivKey()
+	if err != nil {
+		t.Fatalf("ECPrivKey: %v", err)
+	}
+
+	if got := hex.EncodeToString(priv.Serialize()); got != wantPriv {
+		t.Errorf("child priv = %s, want %s", got, wantPriv)
+	}
+	if got := hex.EncodeToString(child.chainCode); got != wantChain {
+		t.Errorf("child chain code = %s, want %s", got, wantChain)
+	}
+	if child.Depth() != 1 {
+		t.Errorf("child depth = %d, want 1", child.Depth())
+	}
+}
+
+// TestDerivePath checks that DerivePath's hardened-apostrophe parsing
+// produces the same key as chaining Child calls by hand.
+func TestDerivePath(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+
+	master, err := NewMaster(seed)
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+
+	viaPath, err := master.DerivePath("m/44'/131'/0'/0/0")
+	if err != nil {
+		t.Fatalf("DerivePath: %v", err)
+	}
+
+	want := master
+	for _, idx := range []uint32{
+		HardenedKeyStart + 44,
+		HardenedKeyStart + FactomCoinType,
+		HardenedKeyStart,
+		0,
+		0,
+	} {
+		want, err = want.Child(idx)
+		if err != nil {
+			t.Fatalf("Child(%d): %v", idx, err)
+		}
+	}
+
+	privA, _ := viaPath.ECPrivKey()
+	privB, _ := want.ECPrivKey()
+	if hex.EncodeToString(privA.Serialize()) != hex.EncodeToString(privB.Serialize()) {
+		t.Errorf("DerivePath produced a different key than manual Child chaining")
+	}
+}
+
+// TestStringRoundTrip checks that String's Base58Check encoding parses
+// back into an equal extended key via NewKeyFromString.
+func TestStringRoundTrip(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+
+	master, err := NewMaster(seed)
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+
+	child, err := master.DerivePath("m/44'/131'/0'/0/0")
+	if err != nil {
+		t.Fatalf("DerivePath: %v", err)
+	}
+
+	parsed, err := NewKeyFromString(child.String())
+	if err != nil {
+		t.Fatalf("NewKeyFromString: %v", err)
+	}
+
+	privWant, _ := child.ECPrivKey()
+	privGot, _ := parsed.ECPrivKey()
+	if hex.EncodeToString(privGot.Serialize()) != hex.EncodeToString(privWant.Serialize()) {
+		t.Errorf("round-tripped private key does not match original")
+	}
+	if hex.EncodeToString(parsed.chainCode) != hex.EncodeToString(child.chainCode) {
+		t.Errorf("round-tripped chain code does not match original")
+	}
+	if parsed.Depth() != child.Depth() {
+		t.Errorf("round-tripped depth = %d, want %d", parsed.Depth(), child.Depth())
+	}
+
+	pub, err := master.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: %v", err)
+	}
+	parsedPub, err := NewKeyFromString(pub.String())
+	if err != nil {
+		t.Fatalf("NewKeyFromString(neutered): %v", err)
+	}
+	if parsedPub.IsPrivate() {
+		t.Errorf("neutered key round-tripped as private")
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2015 Factom Foundation
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package common
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// leafHashPrefix and nodeHashPrefix domain-separate leaf and internal node
+// hashes so a crafted entry can never collide with an internal node of the
+// same tree (the classic second-preimage weakness in unprefixed Merkle
+// trees).
+const (
+	leafHashPrefix = 0x00
+	nodeHashPrefix = 0x01
+)
+
+// MerkleProof is an audit path proving that a single entry is included
+// under a Merkle root, so a light client can verify membership without
+// downloading the whole chain.
+type MerkleProof struct {
+	LeafIndex uint32
+	LeafHash  *Hash
+	Siblings  []*Hash
+
+	// IsRight[i] is true when Siblings[i] is the right-hand sibling of
+	// the node being hashed at that level, i.e. the running hash goes on
+	// the left.
+	IsRight []bool
+}
+
+// entryHash returns the double-SHA256 hash of an entry's binary encoding,
+// prefixed with leafHashPrefix so it can never collide with an internal
+// node hash of the same tree.
+func entryHash(e *Entry) *Hash {
+	bin, _ := e.MarshalBinary()
+	first := sha256.Sum256(append([]byte{leafHashPrefix}, bin...))
+	second := sha256.Sum256(first[:])
+	return &Hash{Bytes: second[:]}
+}
+
+// nodeHash combines two Merkle tree nodes via double-SHA256 over their
+// concatenation, prefixed with nodeHashPrefix so it can never collide with
+// a leaf hash of the same tree.
+func nodeHash(left, right *Hash) *Hash {
+	buf := append([]byte{nodeHashPrefix}, left.Bytes...)
+	buf = append(buf, right.Bytes...)
+	first := sha256.Sum256(buf)
+	second := sha256.Sum256(first[:])
+	return &Hash{Bytes: second[:]}
+}
+
+// merkleLevels computes every level of the Merkle tree over entries'
+// hashes, level[0] being the leaves. A lone node at any level is promoted
+// unchanged to the next level rather than being duplicated.
+func merkleLevels(entries []*Entry) [][]*Hash {
+	leaves := make([]*Hash, len(entries))
+	for i, e := range entries {
+		leaves[i] = entryHash(e)
+	}
+
+	levels := [][]*Hash{leaves}
+	for level := leaves; len(level) > 1; {
+		next := make([]*Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, nodeHash(level[i], level[i+1]))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+
+	return levels
+}
+
+// MerkleRoot computes the Merkle root over the entry hashes of entries, in
+// order. It returns nil for an empty chain.
+func MerkleRoot(entries []*Entry) *Hash {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	levels := merkleLevels(entries)
+	return levels[len(levels)-1][0]
+}
+
+// Prove builds a MerkleProof that e is included in chain, verifiable
+// against MerkleRoot(chain). e is matched against chain by entry hash, so
+// it need not be the same pointer as the chain's own entry.
+func (e *Entry) Prove(chain []*Entry) (*MerkleProof, error) {
+	target := entryHash(e)
+
+	levels := merkleLevels(chain)
+	leaves := levels[0]
+
+	index := -1
+	for i, h := range leaves {
+		if bytes.Equal(h.Bytes, target.Bytes) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, errors.New("common: entry not found in chain")
+	}
+
+	proof := &MerkleProof{LeafIndex: uint32(index), LeafHash: target}
+
+	for lvl := 0; lvl < len(levels)-1; lvl++ {
+		level := levels[lvl]
+
+		pairStart := index - index%2
+		if pairStart+1 == len(level) {
+			// Lone node promoted unchanged; it contributes no sibling.
+			index = len(levels[lvl+1]) - 1
+			continue
+		}
+
+		if index%2 == 0 {
+			proof.Siblings = append(proof.Siblings, level[index+1])
+			proof.IsRight = append(proof.IsRight, true)
+		} else {
+			proof.Siblings = append(proof.Siblings, level[index-1])
+			proof.IsRight = append(proof.IsRight, false)
+		}
+
+		index /= 2
+	}
+
+	return proof, nil
+}
+
+// Verify reports whether p proves its LeafHash is included under root.
+func (p *MerkleProof) Verify(root *Hash) bool {
+	cur := p.LeafHash
+	for i, sibling := range p.Siblings {
+		if p.IsRight[i] {
+			cur = nodeHash(cur, sibling)
+		} else {
+			cur = nodeHash(sibling, cur)
+		}
+	}
+	return bytes.Equal(cur.Bytes, root.Bytes)
+}
+
+// MarshalBinary encodes p as: 4 byte leaf index, 32 byte leaf hash, 2 byte
+// sibling count, then for each sibling a 1 byte direction (1 = right, 0 =
+// left) followed by its 32 byte hash.
+func (p *MerkleProof) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	binary.Write(buf, binary.BigEndian, p.LeafIndex)
+	buf.Write(p.LeafHash.Bytes)
+	binary.Write(buf, binary.BigEndian, uint16(len(p.Siblings)))
+
+	for i, sibling := range p.Siblings {
+		var dir byte
+		if p.IsRight[i] {
+			dir = 1
+		}
+		buf.WriteByte(dir)
+		buf.Write(sibling.Bytes)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes p from the format written by MarshalBinary.
+func (p *MerkleProof) UnmarshalBinary(d []byte) error {
+	buf := bytes.NewBuffer(d)
+
+	if err := binary.Read(buf, binary.BigEndian, &p.LeafIndex); err != nil {
+		return err
+	}
+
+	leafHash := make([]byte, 32)
+	if _, err := io.ReadFull(buf, leafHash); err != nil {
+		return err
+	}
+	p.LeafHash = &Hash{Bytes: leafHash}
+
+	var count uint16
+	if err := binary.Read(buf, binary.BigEndian, &count); err != nil {
+		return err
+	}
+
+	p.Siblings = make([]*Hash, count)
+	p.IsRight = make([]bool, count)
+
+	for i := 0; i < int(count); i++ {
+		dir, err := buf.ReadByte()
+		if err != nil {
+			return err
+		}
+		p.IsRight[i] = dir == 1
+
+		h := make([]byte, 32)
+		if _, err := io.ReadFull(buf, h); err != nil {
+			return err
+		}
+		p.Siblings[i] = &Hash{Bytes: h}
+	}
+
+	return nil
+}